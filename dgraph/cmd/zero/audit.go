@@ -0,0 +1,269 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/dgraph-io/dgraph/ee/enc"
+	"github.com/golang/glog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// auditEvent is a single structured record of a mutating admin operation
+// performed against this Zero. Events are appended as newline-delimited
+// JSON, or as newline-delimited base64 when --audit.encrypt_key_file is set
+// (see auditLogger.log).
+type auditEvent struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Endpoint  string        `json:"endpoint"`
+	Principal string        `json:"principal"`
+	SourceIP  string        `json:"source_ip"`
+	Params    string        `json:"params,omitempty"`
+	Decision  string        `json:"decision"`
+	Error     string        `json:"error,omitempty"`
+	Latency   time.Duration `json:"latency_ns"`
+	RaftIndex uint64        `json:"raft_index,omitempty"`
+}
+
+// auditLogger writes structured audit events to a rotated, optionally
+// encrypted log file. It is safe for concurrent use.
+type auditLogger struct {
+	sync.Mutex
+	out *lumberjack.Logger
+	gcm cipher.AEAD
+}
+
+var auditor *auditLogger
+
+// initAuditLogger wires up the audit subsystem based on the --audit.*
+// flags. It is a no-op (auditor stays nil) if --audit.dir is unset, in which
+// case auditUnaryInterceptor and auditHTTPMiddleware simply pass requests
+// through unaudited.
+func initAuditLogger() error {
+	dir := Zero.Conf.GetString("audit.dir")
+	if dir == "" {
+		return nil
+	}
+	if !enc.EeBuild {
+		return fmt.Errorf("audit logging requires an Enterprise Edition build")
+	}
+
+	al := &auditLogger{
+		out: &lumberjack.Logger{
+			Filename:   dir + "/zero_audit.log",
+			MaxSize:    100, // megabytes
+			MaxAge:     30,  // days
+			Compress:   Zero.Conf.GetBool("audit.compress"),
+			LocalTime:  true,
+		},
+	}
+
+	if keyFile := Zero.Conf.GetString("audit.encrypt_key_file"); keyFile != "" {
+		key, err := enc.ReadKeyFromFile(keyFile)
+		if err != nil {
+			return err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		al.gcm = gcm
+	}
+
+	auditor = al
+	glog.Infof("Audit logging enabled, writing to %s", dir)
+	return nil
+}
+
+func (al *auditLogger) log(ev *auditEvent) {
+	if al == nil {
+		return
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		glog.Errorf("audit: failed to marshal event: %v", err)
+		return
+	}
+	if al.gcm != nil {
+		nonce := make([]byte, al.gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			glog.Errorf("audit: failed to generate nonce: %v", err)
+			return
+		}
+		sealed := al.gcm.Seal(nonce, nonce, data, nil)
+		// AES-GCM ciphertext/nonce bytes can themselves contain 0x0A, and
+		// newline is the only framing this log format has, so base64-encode
+		// the sealed blob rather than writing it raw -- otherwise a single
+		// encrypted event could be split across "lines" by any reader.
+		data = make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+		base64.StdEncoding.Encode(data, sealed)
+	}
+	data = append(data, '\n')
+
+	al.Lock()
+	defer al.Unlock()
+	if _, err := al.out.Write(data); err != nil {
+		glog.Errorf("audit: failed to write event: %v", err)
+	}
+}
+
+// principalFromContext extracts the client certificate's Common Name to use
+// as the audited principal, falling back to "unknown" when mTLS isn't in use.
+func principalFromContext(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return "unknown"
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return "unknown"
+	}
+	if len(tlsInfo.State.PeerCertificates) == 0 {
+		return "unknown"
+	}
+	cert := tlsInfo.State.PeerCertificates[0]
+	return cert.Subject.CommonName
+}
+
+func sourceIPFromContext(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	return p.Addr.String()
+}
+
+// committedRaftIndex returns the Raft index committed on this node's log as
+// of "now", used as the audited event's resulting Raft index. For mutating
+// calls that go through st.node.proposeAndWait, the proposal is already
+// committed by the time the handler returns, so this is an accurate
+// approximation of the index the operation landed at without requiring every
+// handler to separately thread its proposal's index back out.
+func (st *state) committedRaftIndex() uint64 {
+	if st == nil || st.node == nil {
+		return 0
+	}
+	return st.node.Raft().Status().Commit
+}
+
+// auditedGRPCMethods is the allowlist of pb.Zero RPCs that mutate cluster
+// state and are therefore worth auditing. Everything else -- Timestamps,
+// AssignUids, ShouldServe, and other RPCs every Alpha calls on the hot path
+// of every transaction -- passes through unaudited so auditUnaryInterceptor
+// doesn't serialize every unary call through auditLogger.log's mutex.
+//
+// This is currently empty: the mutating admin operations added alongside
+// this subsystem (peer join/promote) are reachable only over the
+// /addZeroPeer and /promoteLearner HTTP endpoints, which auditHTTPMiddleware
+// already covers -- they were never actually wired into the pb.Zero gRPC
+// service descriptor (that requires a protos/pb change this checkout
+// doesn't carry), so there is no "/pb.Zero/AddZeroPeer" or
+// "/pb.Zero/PromoteLearner" FullMethod for a gRPC client to ever hit. Add
+// entries here once a real mutating pb.Zero RPC exists to audit.
+var auditedGRPCMethods = map[string]bool{}
+
+// auditUnaryInterceptor logs the gRPC calls whose method is listed in
+// auditedGRPCMethods as structured JSON audit events. That map is empty, so
+// every call currently passes through unaudited.
+func (st *state) auditUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (any, error) {
+
+	if auditor == nil || !auditedGRPCMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	ev := &auditEvent{
+		Timestamp: start,
+		Endpoint:  info.FullMethod,
+		Principal: principalFromContext(ctx),
+		SourceIP:  sourceIPFromContext(ctx),
+		Params:    fmt.Sprintf("%+v", req),
+		Latency:   time.Since(start),
+		Decision:  "allowed",
+		RaftIndex: st.committedRaftIndex(),
+	}
+	if err != nil {
+		ev.Decision = "error"
+		ev.Error = err.Error()
+	}
+	auditor.log(ev)
+	return resp, err
+}
+
+// auditHTTPMiddleware wraps a mutating admin HTTP handler (e.g. /removeNode,
+// /moveTablet, /assign, /enterpriseLicense) so every call is recorded.
+func (st *state) auditHTTPMiddleware(endpoint string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auditor == nil {
+			h(w, r)
+			return
+		}
+
+		start := time.Now()
+		sw := &statusRecordingWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, r)
+
+		principal := "unknown"
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			principal = certCN(r.TLS.PeerCertificates[0])
+		}
+		decision := "allowed"
+		if sw.status >= 400 {
+			decision = "error"
+		}
+		auditor.log(&auditEvent{
+			Timestamp: start,
+			Endpoint:  endpoint,
+			Principal: principal,
+			SourceIP:  r.RemoteAddr,
+			Params:    r.URL.RawQuery,
+			Decision:  decision,
+			Latency:   time.Since(start),
+			RaftIndex: st.committedRaftIndex(),
+		})
+	}
+}
+
+func certCN(cert *x509.Certificate) string {
+	if cert == nil {
+		return "unknown"
+	}
+	return cert.Subject.CommonName
+}
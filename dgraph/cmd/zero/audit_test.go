@@ -0,0 +1,155 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+func TestAuditEventJSONShape(t *testing.T) {
+	ev := &auditEvent{
+		Timestamp: time.Unix(0, 0).UTC(),
+		Endpoint:  "/moveTablet",
+		Principal: "admin",
+		SourceIP:  "127.0.0.1:1234",
+		Params:    "tablet=1&group=2",
+		Decision:  "allowed",
+		Latency:   5 * time.Millisecond,
+		RaftIndex: 42,
+	}
+	data, err := json.Marshal(ev)
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, "/moveTablet", got["endpoint"])
+	require.Equal(t, "admin", got["principal"])
+	require.Equal(t, "allowed", got["decision"])
+	require.EqualValues(t, 42, got["raft_index"])
+	require.NotContains(t, got, "error", "omitempty error field should be absent on success")
+}
+
+func TestAuditUnaryInterceptorPassesThroughWithoutAuditor(t *testing.T) {
+	prev := auditor
+	auditor = nil
+	defer func() { auditor = prev }()
+
+	st := &state{}
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return "resp", nil
+	}
+	resp, err := st.auditUnaryInterceptor(context.Background(), "req", &grpc.UnaryServerInfo{}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "resp", resp)
+	require.True(t, called)
+}
+
+func TestAuditUnaryInterceptorSkipsUnauditedMethods(t *testing.T) {
+	prev := auditor
+	auditor = &auditLogger{} // non-nil, but its .out must never be touched below
+	defer func() { auditor = prev }()
+
+	st := &state{}
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return "resp", nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.Zero/Timestamps"}
+	resp, err := st.auditUnaryInterceptor(context.Background(), "req", info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "resp", resp)
+	require.True(t, called, "handler should still run for unaudited methods")
+}
+
+func TestAuditHTTPMiddlewareRecordsErrorDecisionOnFailure(t *testing.T) {
+	prev := auditor
+	logFile := t.TempDir() + "/audit.log"
+	auditor = &auditLogger{out: &lumberjack.Logger{Filename: logFile}}
+	defer func() { auditor = prev }()
+
+	st := &state{}
+	handler := st.auditHTTPMiddleware("/removeNode", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/removeNode", nil)
+	handler(httptest.NewRecorder(), req)
+
+	data, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+
+	var got auditEvent
+	require.NoError(t, json.Unmarshal(data[:len(data)-1], &got)) // trim trailing '\n'
+	require.Equal(t, "error", got.Decision)
+}
+
+func TestAuditLoggerEncryptedEventsAreBase64Lines(t *testing.T) {
+	block, err := aes.NewCipher([]byte("0123456789abcdef0123456789abcdef"[:32]))
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	logFile := t.TempDir() + "/audit.log"
+	al := &auditLogger{out: &lumberjack.Logger{Filename: logFile}, gcm: gcm}
+
+	al.log(&auditEvent{Endpoint: "/removeNode", Decision: "allowed"})
+	al.log(&auditEvent{Endpoint: "/moveTablet", Decision: "error"})
+
+	data, err := os.ReadFile(logFile)
+	require.NoError(t, err)
+	require.Equal(t, byte('\n'), data[len(data)-1])
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 2)
+
+	wantEndpoint := []string{"/removeNode", "/moveTablet"}
+	for i, line := range lines {
+		sealed, err := base64.StdEncoding.DecodeString(line)
+		require.NoError(t, err, "each line must be valid base64, not raw ciphertext")
+
+		require.Greater(t, len(sealed), gcm.NonceSize())
+		nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+		plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+		require.NoError(t, err)
+
+		var got auditEvent
+		require.NoError(t, json.Unmarshal(plain, &got))
+		require.Equal(t, wantEndpoint[i], got.Endpoint)
+	}
+}
+
+func TestCommittedRaftIndexNilState(t *testing.T) {
+	var st *state
+	require.EqualValues(t, 0, st.committedRaftIndex())
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+// loadConfigFile merges settings from the file named by the --config flag
+// (TOML or YAML, inferred from its extension) into Zero.Conf. Precedence,
+// highest first, is: command-line flags > environment variables (DGRAPH_ZERO_*)
+// > config file > flag defaults. Since Zero.Conf is a viper instance that
+// already has flags and the env prefix bound, merging the file in preserves
+// that order -- any value explicitly set via flag or env simply overrides what
+// the file provides. run() calls this after its enable_sentry check, so
+// enable_sentry is not read from the file.
+func loadConfigFile() error {
+	path := Zero.Conf.GetString("config")
+	if path == "" {
+		return nil
+	}
+
+	Zero.Conf.SetConfigFile(path)
+	if err := Zero.Conf.MergeInConfig(); err != nil {
+		return fmt.Errorf("while reading config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// exportCmd is the "zero export" subcommand. It writes the currently
+// effective configuration (flag defaults overridden by env vars, an existing
+// --config file, and any flags passed on this invocation) back out to a file,
+// so it can be checked in and reused with `zero --config`.
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the currently effective Zero configuration to a file",
+	Long: `
+The export command writes out every Zero setting -- as resolved from flag
+defaults, DGRAPH_ZERO_* environment variables, an existing --config file, and
+any flags passed to this invocation -- to a TOML or YAML file, so the
+resulting configuration can be reused with 'dgraph zero --config'. Zero reads
+enable_sentry before it loads --config, so that one setting in the file has
+no effect.
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out, err := cmd.Flags().GetString("out")
+		if err != nil {
+			return err
+		}
+		if out == "" {
+			out = "zero.toml"
+		}
+
+		if err := loadConfigFile(); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(out), 0700); err != nil && filepath.Dir(out) != "." {
+			return err
+		}
+
+		switch filepath.Ext(out) {
+		case ".yaml", ".yml":
+			Zero.Conf.SetConfigType("yaml")
+		default:
+			Zero.Conf.SetConfigType("toml")
+		}
+		if err := Zero.Conf.WriteConfigAs(out); err != nil {
+			return fmt.Errorf("while writing config to %s: %w", out, err)
+		}
+
+		fmt.Printf("Wrote effective Zero configuration to %s\n", out)
+		return nil
+	},
+}
+
+// registerConfigFlags adds the export subcommand and the --config flag to
+// Zero.Cmd. It is called from run.go's init, after Zero.Cmd is created.
+func registerConfigFlags() {
+	exportCmd.Flags().String("out", "zero.toml",
+		"File to write the exported configuration to. Extension (.toml/.yaml) selects the format.")
+	Zero.Cmd.AddCommand(exportCmd)
+
+	Zero.Cmd.PersistentFlags().String("config", "",
+		"Path to a TOML or YAML file with Zero configuration. "+
+			"Values here are overridden by environment variables and command-line flags.")
+	if Zero.Conf != nil {
+		x.Check(Zero.Conf.BindPFlag("config", Zero.Cmd.PersistentFlags().Lookup("config")))
+	}
+}
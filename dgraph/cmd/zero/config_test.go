@@ -0,0 +1,62 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFileNoConfigFlagIsNoop(t *testing.T) {
+	defer withConf(nil)()
+	require.NoError(t, loadConfigFile())
+}
+
+func TestLoadConfigFilePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "zero.toml")
+	require.NoError(t, ioutil.WriteFile(cfgPath, []byte("rebalance_interval = \"3m\"\n"), 0600))
+
+	defer withConf(map[string]any{"config": cfgPath})()
+	// Simulate a flag explicitly passed on the command line, which must win
+	// over the config file.
+	Zero.Conf.Set("rebalance_interval", "9m")
+
+	require.NoError(t, loadConfigFile())
+	require.Equal(t, 9*time.Minute, Zero.Conf.GetDuration("rebalance_interval"))
+}
+
+func TestLoadConfigFileAppliesFileValueWhenFlagUnset(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "zero.toml")
+	require.NoError(t, ioutil.WriteFile(cfgPath, []byte("rebalance_interval = \"3m\"\n"), 0600))
+
+	defer withConf(map[string]any{"config": cfgPath})()
+
+	require.NoError(t, loadConfigFile())
+	require.Equal(t, 3*time.Minute, Zero.Conf.GetDuration("rebalance_interval"))
+}
+
+func TestExportCmdOutFlagDefault(t *testing.T) {
+	out, err := exportCmd.Flags().GetString("out")
+	require.NoError(t, err)
+	require.Equal(t, "zero.toml", out)
+}
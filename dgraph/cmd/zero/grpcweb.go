@@ -0,0 +1,79 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
+	"google.golang.org/grpc"
+
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+)
+
+// grpcWebHandler wraps a gRPC server so that browsers and firewalled clients
+// can call pb.Zero RPCs -- including streaming ones like StreamMembership and
+// Oracle -- over grpc-web or a websocket, without a native gRPC stack.
+// maxMessageSize bounds the buffered message size on the websocket wrapper;
+// it defaults to x.GrpcMaxSize since streamed responses can otherwise exceed
+// the small frame buffers used by common websocket proxy libraries.
+//
+// Since pb.Zero fronts mutating admin RPCs, cross-origin calls are rejected
+// unless the caller's origin appears in --grpc_web.allowed_origins; by
+// default (the flag unset) only same-origin requests are allowed.
+func grpcWebHandler(s *grpc.Server, maxMessageSize int, allowedOrigins []string) http.Handler {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, o := range allowedOrigins {
+		allowed[o] = true
+	}
+
+	wrapped := grpcweb.WrapServer(s,
+		grpcweb.WithWebsockets(true),
+		grpcweb.WithWebsocketMessageBufferSize(maxMessageSize),
+		grpcweb.WithOriginFunc(func(origin string) bool { return allowed[origin] }),
+	)
+
+	glog.Infof("grpc-web gateway enabled, max message size: %d bytes, allowed origins: %v",
+		maxMessageSize, allowedOrigins)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/api/grpc")
+		wrapped.ServeHTTP(w, r)
+	})
+}
+
+func maxGrpcWebMessageSize() int {
+	size := Zero.Conf.GetInt("grpc_web.max_message_size")
+	if size <= 0 {
+		return x.GrpcMaxSize
+	}
+	return size
+}
+
+// allowedGrpcWebOrigins returns the configured cross-origin allowlist for the
+// grpc-web gateway. An empty, unset flag means no cross-origin caller is
+// allowed -- only requests the websocket/grpc-web wrapper treats as
+// same-origin (no Origin header) get through.
+func allowedGrpcWebOrigins() []string {
+	raw := Zero.Conf.GetString("grpc_web.allowed_origins")
+	if raw == "" {
+		return nil
+	}
+	return strings.Split(raw, ",")
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dgraph-io/dgraph/x"
+)
+
+func TestMaxGrpcWebMessageSizeDefault(t *testing.T) {
+	defer withConf(map[string]any{"grpc_web.max_message_size": 0})()
+	require.Equal(t, x.GrpcMaxSize, maxGrpcWebMessageSize())
+}
+
+func TestMaxGrpcWebMessageSizeExplicit(t *testing.T) {
+	defer withConf(map[string]any{"grpc_web.max_message_size": 4096})()
+	require.Equal(t, 4096, maxGrpcWebMessageSize())
+}
+
+func TestAllowedGrpcWebOriginsUnsetIsNil(t *testing.T) {
+	defer withConf(map[string]any{"grpc_web.allowed_origins": ""})()
+	require.Nil(t, allowedGrpcWebOrigins())
+}
+
+func TestAllowedGrpcWebOriginsParsesList(t *testing.T) {
+	defer withConf(map[string]any{
+		"grpc_web.allowed_origins": "https://a.example.com,https://b.example.com",
+	})()
+	require.Equal(t,
+		[]string{"https://a.example.com", "https://b.example.com"},
+		allowedGrpcWebOrigins())
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/dgraph-io/dgraph/ee/enc"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+)
+
+// newKeyProvider builds the enc.KeyProvider selected by --encryption.kms,
+// reading/writing the key file named by --encryption_key_file.
+func newKeyProvider(kind, keyFile string) (enc.KeyProvider, error) {
+	if keyFile == "" {
+		return nil, fmt.Errorf("--encryption_key_file is required when --encryption.kms is set")
+	}
+	switch kind {
+	case "file":
+		return &localKeyProvider{path: keyFile}, nil
+	case "aws", "gcp", "vault":
+		backend, err := enc.KMSBackend(kind)
+		if err != nil {
+			return nil, err
+		}
+		return &kmsWrappedKeyProvider{service: kind, keyFile: keyFile, kms: backend}, nil
+	default:
+		return nil, fmt.Errorf("unknown --encryption.kms provider: %q", kind)
+	}
+}
+
+// localKeyProvider keeps the DEK itself, unencrypted, in keyFile. Rotate
+// generates a fresh 32-byte AES-256 key and overwrites the file with it.
+type localKeyProvider struct {
+	path string
+}
+
+func (p *localKeyProvider) DataKey() ([]byte, error) {
+	return enc.ReadKeyFromFile(p.path)
+}
+
+func (p *localKeyProvider) Rotate() ([]byte, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("while generating new WAL data encryption key: %w", err)
+	}
+	if err := ioutil.WriteFile(p.path, dek, 0600); err != nil {
+		return nil, fmt.Errorf("while writing rotated WAL data encryption key to %s: %w", p.path, err)
+	}
+	return dek, nil
+}
+
+// kmsWrappedKeyProvider keeps only a KMS-wrapped DEK on disk; unwrap/wrap
+// round-trips through the enc.KMSUnwrapper registered for p.service.
+type kmsWrappedKeyProvider struct {
+	service string
+	keyFile string
+	kms     enc.KMSUnwrapper
+}
+
+func (p *kmsWrappedKeyProvider) DataKey() ([]byte, error) {
+	wrapped, err := enc.ReadKeyFromFile(p.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("while reading wrapped DEK for %s: %w", p.service, err)
+	}
+	dek, err := p.kms.Unwrap(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("while unwrapping DEK via %s: %w", p.service, err)
+	}
+	return dek, nil
+}
+
+func (p *kmsWrappedKeyProvider) Rotate() ([]byte, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("while generating new WAL data encryption key: %w", err)
+	}
+	wrapped, err := p.kms.Wrap(dek)
+	if err != nil {
+		return nil, fmt.Errorf("while wrapping new DEK via %s: %w", p.service, err)
+	}
+	if err := ioutil.WriteFile(p.keyFile, wrapped, 0600); err != nil {
+		return nil, fmt.Errorf("while writing rotated wrapped DEK to %s: %w", p.keyFile, err)
+	}
+	return dek, nil
+}
+
+// startKeyRotation periodically asks kp to rotate the WAL data encryption
+// key. Badger only re-wraps its on-disk key registry with the master key
+// passed to badger.OpenManaged at open time, so a rotated key can't take
+// effect on the already-open store in place; instead, once the new key is
+// safely persisted (to the local key file or the KMS), startKeyRotation
+// triggers the same graceful shutdown path SIGTERM uses, relying on the
+// process supervisor (systemd/k8s) to restart Zero, which reopens Badger and
+// re-wraps the registry with the rotated key -- still without touching any
+// SSTable.
+//
+// Every replica in a cluster is typically started with the same
+// --encryption.key_rotation_interval, so their tickers would otherwise fire
+// within moments of each other and restart together, risking a full Raft
+// quorum outage at rotation time. The first tick is staggered by a random
+// jitter so replicas restart at different times; a failed rotation retries
+// on the unjittered interval.
+func startKeyRotation(kp enc.KeyProvider, interval time.Duration, restart func(), closer *z.Closer) {
+	if interval <= 0 {
+		closer.Done()
+		return
+	}
+	go func() {
+		defer closer.Done()
+		timer := time.NewTimer(interval + rotationJitter(interval))
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+				if _, err := kp.Rotate(); err != nil {
+					glog.Errorf("key rotation failed: %v", err)
+					timer.Reset(interval)
+					continue
+				}
+				glog.Infoln("WAL data encryption key rotated; restarting to apply it " +
+					"(Badger re-wraps its key registry on open; SSTables are untouched)")
+				restart()
+				return
+			case <-closer.HasBeenClosed():
+				return
+			}
+		}
+	}()
+}
+
+// rotationJitter returns a random duration in [0, interval/5) used to
+// stagger the first key-rotation tick across replicas sharing the same
+// rotation interval, so a whole cluster doesn't restart for rotation at the
+// same moment. Returns 0 if interval is too small to jitter meaningfully.
+func rotationJitter(interval time.Duration) time.Duration {
+	span := int64(interval / 5)
+	if span <= 0 {
+		return 0
+	}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0
+	}
+	n := int64(binary.BigEndian.Uint64(b[:]))
+	if n < 0 {
+		n = -n
+	}
+	return time.Duration(n % span)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyProviderFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0600))
+
+	kp, err := newKeyProvider("file", keyFile)
+	require.NoError(t, err)
+
+	dek, err := kp.DataKey()
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789abcdef0123456789abcdef"), dek)
+}
+
+func TestNewKeyProviderRequiresKeyFile(t *testing.T) {
+	_, err := newKeyProvider("file", "")
+	require.Error(t, err)
+}
+
+func TestNewKeyProviderUnknownKind(t *testing.T) {
+	_, err := newKeyProvider("azure", "/tmp/whatever")
+	require.Error(t, err)
+}
+
+func TestNewKeyProviderUnregisteredKMSBackend(t *testing.T) {
+	// No ee/enc/{aws,gcp,vault} subpackage is imported by this test binary, so
+	// the registry lookup should fail with an explicit error rather than
+	// silently returning a no-op backend.
+	_, err := newKeyProvider("aws", "/tmp/whatever")
+	require.Error(t, err)
+}
+
+func TestRotationJitterWithinBounds(t *testing.T) {
+	interval := 10 * time.Minute
+	for i := 0; i < 100; i++ {
+		j := rotationJitter(interval)
+		require.GreaterOrEqual(t, j, time.Duration(0))
+		require.Less(t, j, interval/5)
+	}
+}
+
+func TestRotationJitterZeroForTinyInterval(t *testing.T) {
+	require.Zero(t, rotationJitter(time.Nanosecond))
+}
+
+func TestLocalKeyProviderRotateWritesNewKey(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("initial-key-contents-32-bytes!!!"), 0600))
+
+	kp := &localKeyProvider{path: keyFile}
+	rotated, err := kp.Rotate()
+	require.NoError(t, err)
+	require.Len(t, rotated, 32)
+
+	onDisk, err := ioutil.ReadFile(keyFile)
+	require.NoError(t, err)
+	require.Equal(t, rotated, onDisk)
+}
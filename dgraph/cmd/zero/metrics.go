@@ -0,0 +1,265 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	ocprom "contrib.go.opencensus.io/exporter/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opencensus.io/plugin/ocgrpc"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/ristretto/z"
+	"github.com/golang/glog"
+)
+
+// Measures recorded by Zero. These are in addition to the gRPC server
+// measures that ocgrpc records for us (see registerPrometheusMetrics).
+var (
+	mRaftIsLeader = stats.Int64("zero/raft_is_leader",
+		"Set to 1 if this Zero is the current Raft leader, 0 otherwise.", stats.UnitDimensionless)
+	mRaftTerm = stats.Int64("zero/raft_term",
+		"Current Raft term as seen by this Zero.", stats.UnitDimensionless)
+	mProposalLatencyMs = stats.Float64("zero/proposal_latency_ms",
+		"Latency of Raft proposals proposed by this Zero.", stats.UnitMilliseconds)
+	mTabletMoves = stats.Int64("zero/tablet_moves",
+		"Number of tablet moves, tagged by result.", stats.UnitDimensionless)
+	mRebalanceDurationMs = stats.Float64("zero/rebalance_duration_ms",
+		"Duration of a predicate rebalance run.", stats.UnitMilliseconds)
+	mGroupMembers = stats.Int64("zero/group_members",
+		"Number of members in a group, tagged by group id.", stats.UnitDimensionless)
+	mWalBadgerSizeBytes = stats.Int64("zero/wal_badger_size_bytes",
+		"On-disk size (LSM + value log) of the WAL Badger store.", stats.UnitBytes)
+	mVlogGCRuns = stats.Int64("zero/vlog_gc_runs",
+		"Number of value log GC runs triggered against the WAL store.", stats.UnitDimensionless)
+)
+
+// KeyResult tags a measurement with the outcome of the operation it describes,
+// e.g. "success" or "failure".
+var KeyResult, _ = tag.NewKey("result")
+
+// KeyGroup tags a measurement with the Raft group ID it pertains to.
+var KeyGroup, _ = tag.NewKey("group")
+
+var defaultViews = []*view.View{
+	{
+		Name:        "zero/raft_is_leader",
+		Measure:     mRaftIsLeader,
+		Description: mRaftIsLeader.Description(),
+		Aggregation: view.LastValue(),
+	},
+	{
+		Name:        "zero/raft_term",
+		Measure:     mRaftTerm,
+		Description: mRaftTerm.Description(),
+		Aggregation: view.LastValue(),
+	},
+	{
+		Name:        "zero/proposal_latency_ms",
+		Measure:     mProposalLatencyMs,
+		Description: mProposalLatencyMs.Description(),
+		Aggregation: view.Distribution(1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000),
+	},
+	{
+		Name:        "zero/tablet_moves_total",
+		Measure:     mTabletMoves,
+		Description: mTabletMoves.Description(),
+		TagKeys:     []tag.Key{KeyResult},
+		Aggregation: view.Count(),
+	},
+	{
+		Name:        "zero/rebalance_duration_ms",
+		Measure:     mRebalanceDurationMs,
+		Description: mRebalanceDurationMs.Description(),
+		Aggregation: view.Distribution(10, 50, 100, 500, 1000, 5000, 10000, 30000, 60000),
+	},
+	{
+		Name:        "zero/group_members",
+		Measure:     mGroupMembers,
+		Description: mGroupMembers.Description(),
+		TagKeys:     []tag.Key{KeyGroup},
+		Aggregation: view.LastValue(),
+	},
+	{
+		Name:        "zero/wal_badger_size_bytes",
+		Measure:     mWalBadgerSizeBytes,
+		Description: mWalBadgerSizeBytes.Description(),
+		Aggregation: view.LastValue(),
+	},
+	{
+		Name:        "zero/vlog_gc_runs_total",
+		Measure:     mVlogGCRuns,
+		Description: mVlogGCRuns.Description(),
+		Aggregation: view.Count(),
+	},
+}
+
+// registerPrometheusMetrics registers Zero's OpenCensus views (Raft status,
+// proposal latency, tablet moves, rebalance duration, group membership, WAL
+// Badger size/GC) and ocgrpc's default gRPC server views (per-method RPC
+// latency and counts) with Prometheus, and returns an http.Handler serving the
+// resulting exposition on the caller's chosen route. No HTTP request duration
+// view is registered: Zero's HTTP handlers are not instrumented.
+func registerPrometheusMetrics() (http.Handler, error) {
+	if err := view.Register(defaultViews...); err != nil {
+		return nil, err
+	}
+	if err := view.Register(ocgrpc.DefaultServerViews...); err != nil {
+		return nil, err
+	}
+	exporter, err := ocprom.NewExporter(ocprom.Options{
+		Namespace: "dgraph",
+		Registry:  prometheus.DefaultRegisterer.(*prometheus.Registry),
+	})
+	if err != nil {
+		return nil, err
+	}
+	view.RegisterExporter(exporter)
+	glog.Infoln("Prometheus metrics exporter registered for Zero")
+	return exporter, nil
+}
+
+// statusRecordingWriter captures the HTTP status code a wrapped handler
+// wrote, defaulting to 200 to match http.ResponseWriter's own behavior when
+// WriteHeader is never called.
+type statusRecordingWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// tabletMoveMetricsMiddleware wraps /moveTablet so every call -- success or
+// failure -- is recorded in the zero/tablet_moves_total view.
+func tabletMoveMetricsMiddleware(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusRecordingWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, r)
+		recordTabletMove(sw.status == http.StatusOK)
+	}
+}
+
+// recordTabletMove records the outcome of a single /moveTablet call.
+func recordTabletMove(success bool) {
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	ctx, err := tag.New(context.Background(), tag.Upsert(KeyResult, result))
+	if err != nil {
+		glog.Errorf("metrics: failed to tag tablet move result: %v", err)
+		return
+	}
+	stats.Record(ctx, mTabletMoves.M(1))
+}
+
+// recordGroupMembers records the current voter+learner count for a group.
+// Zero peers are always group 0.
+func recordGroupMembers(group uint64, n int) {
+	ctx, err := tag.New(context.Background(), tag.Upsert(KeyGroup, fmt.Sprintf("%d", group)))
+	if err != nil {
+		glog.Errorf("metrics: failed to tag group members: %v", err)
+		return
+	}
+	stats.Record(ctx, mGroupMembers.M(int64(n)))
+}
+
+// recordProposalLatency records how long a single Raft proposal took to
+// commit, in milliseconds. Call sites elsewhere in this package (e.g. the
+// predicate/tablet move path) that aren't part of this checkout should record
+// the same way once they're wired in.
+func recordProposalLatency(start time.Time) {
+	stats.Record(context.Background(),
+		mProposalLatencyMs.M(float64(time.Since(start))/float64(time.Millisecond)))
+}
+
+// recordRebalanceDuration records how long a single predicate rebalance run
+// took, in milliseconds. The rebalance loop itself (triggered on
+// --rebalance_interval) isn't part of this checkout, so call this the same
+// way recordProposalLatency is called from peer_membership.go once that
+// loop's call site is wired in.
+func recordRebalanceDuration(start time.Time) {
+	stats.Record(context.Background(),
+		mRebalanceDurationMs.M(float64(time.Since(start))/float64(time.Millisecond)))
+}
+
+// pollRaftAndStoreMetrics periodically records Raft leader/term status and
+// the on-disk size of the WAL Badger store until closer is closed.
+func pollRaftAndStoreMetrics(st *state, kv badgerSizer, closer *z.Closer) {
+	defer closer.Done()
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			status := st.node.Raft().Status()
+			isLeader := int64(0)
+			if status.Lead == status.ID {
+				isLeader = 1
+			}
+			stats.Record(context.Background(),
+				mRaftIsLeader.M(isLeader),
+				mRaftTerm.M(int64(status.Term)))
+
+			lsm, vlog := kv.Size()
+			stats.Record(context.Background(), mWalBadgerSizeBytes.M(lsm+vlog))
+		case <-closer.HasBeenClosed():
+			return
+		}
+	}
+}
+
+// badgerSizer is the subset of *badger.DB used by pollRaftAndStoreMetrics,
+// kept as an interface so tests can fake it without opening a real store.
+type badgerSizer interface {
+	Size() (lsm, vlog int64)
+}
+
+// runVlogGCWithMetrics runs Badger's value log GC on the same cadence as
+// x.RunVlogGC, additionally recording each attempted run so the
+// zero/vlog_gc_runs_total view reflects reality.
+func runVlogGCWithMetrics(kv *badger.DB, closer *z.Closer) {
+	defer closer.Done()
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				err := kv.RunValueLogGC(0.7)
+				stats.Record(context.Background(), mVlogGCRuns.M(1))
+				if err != nil {
+					// badger.ErrNoRewrite (nothing left to reclaim) or any
+					// other error both mean: stop until the next tick.
+					break
+				}
+			}
+		case <-closer.HasBeenClosed():
+			return
+		}
+	}
+}
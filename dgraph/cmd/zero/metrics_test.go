@@ -0,0 +1,52 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusRecordingWriterDefaultsTo200(t *testing.T) {
+	rr := httptest.NewRecorder()
+	sw := &statusRecordingWriter{ResponseWriter: rr, status: http.StatusOK}
+	sw.Write([]byte("ok"))
+	require.Equal(t, http.StatusOK, sw.status)
+}
+
+func TestStatusRecordingWriterCapturesExplicitStatus(t *testing.T) {
+	rr := httptest.NewRecorder()
+	sw := &statusRecordingWriter{ResponseWriter: rr, status: http.StatusOK}
+	sw.WriteHeader(http.StatusBadRequest)
+	require.Equal(t, http.StatusBadRequest, sw.status)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestTabletMoveMetricsMiddlewarePassesThroughUnderlyingStatus(t *testing.T) {
+	h := tabletMoveMetricsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/moveTablet", nil)
+	rr := httptest.NewRecorder()
+	h(rr, req)
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// This file exposes zero peer join/promote as the /addZeroPeer and
+// /promoteLearner HTTP endpoints, with the handlers simply decoding query
+// parameters -- the convention the rest of this package's admin endpoints
+// use -- and delegating to AddZeroPeer/PromoteLearner below. Using query
+// params rather than a JSON body also means auditHTTPMiddleware's
+// r.URL.RawQuery capture actually records which id/addr was mutated.
+//
+// AddZeroPeer/PromoteLearner take pb.Zero request/response types and are
+// shaped like gRPC handlers so a pb.Zero RPC surface could be added on top
+// of them, but that wiring -- declaring the RPCs on the pb.Zero service and
+// regenerating pb.go -- hasn't been done: protos/pb isn't part of this
+// checkout. For now these are reachable only via the HTTP endpoints below,
+// which call them directly as plain Go methods; there is no
+// "/pb.Zero/AddZeroPeer" or "/pb.Zero/PromoteLearner" RPC a gRPC client
+// could actually dial. Learner state needs no separate plumbing for /state:
+// addZeroPeer and promoteLearner keep pb.Member.Learner up to date on the
+// membership map that st.getState already serializes in full.
+package zero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.etcd.io/etcd/raft/raftpb"
+
+	"github.com/dgraph-io/dgraph/protos/pb"
+	"github.com/dgraph-io/dgraph/x"
+	"github.com/golang/glog"
+)
+
+// AddZeroPeer handles a request to join a new Zero to the cluster as a Raft
+// learner. The learner catches up on the Raft log without participating in
+// quorum, and is later promoted to a voter via PromoteLearner. This lets
+// operators grow a Zero cluster at runtime instead of only at bootstrap via
+// --peer. It is not wired up as a pb.Zero gRPC RPC (see the file comment
+// above); for now it backs only the /addZeroPeer HTTP endpoint below.
+func (s *Server) AddZeroPeer(ctx context.Context, in *pb.AddZeroPeerRequest) (*pb.Status, error) {
+	if in.Id == 0 || in.Addr == "" {
+		return nil, errors.New("id and addr are required")
+	}
+
+	cc := raftpb.ConfChange{
+		Type:    raftpb.ConfChangeAddLearnerNode,
+		NodeID:  in.Id,
+		Context: []byte(in.Addr),
+	}
+	start := time.Now()
+	err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{
+		Member: &pb.Member{Id: in.Id, Addr: in.Addr, GroupId: 0, Learner: true},
+	})
+	recordProposalLatency(start)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Node.Raft().ProposeConfChange(ctx, cc); err != nil {
+		// The membership proposal above already committed, so pb.Member/
+		// /state now shows this node as a learner, but Raft's own conf state
+		// never actually added it and never will without a retry -- this is
+		// not the same failure as a clean, no-op rejection, so say so.
+		glog.Errorf("Zero peer %#x (%s) was recorded as a learner in membership state, but the "+
+			"Raft conf change to add it failed and was not retried: %v", in.Id, in.Addr, err)
+		return nil, fmt.Errorf("membership state updated but raft conf change failed for peer %#x, "+
+			"cluster state is now inconsistent: %w", in.Id, err)
+	}
+
+	recordGroupMembers(0, len(s.Node.Raft().Status().Progress))
+	glog.Infof("Added Zero peer %#x (%s) as a Raft learner", in.Id, in.Addr)
+	return &pb.Status{Code: 0, Msg: "OK"}, nil
+}
+
+func (st *state) addZeroPeer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		x.SetStatus(w, x.Error, "valid id is required")
+		return
+	}
+	addr := r.URL.Query().Get("addr")
+
+	if _, err := st.zero.AddZeroPeer(r.Context(),
+		&pb.AddZeroPeerRequest{Id: id, Addr: addr}); err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.WriteResponse(w, r, []byte("OK"))
+}
+
+// PromoteLearner promotes a Zero that has caught up as a Raft learner into a
+// full voting member of the cluster. It is not wired up as a pb.Zero gRPC RPC
+// (see the file comment above); for now it backs only the /promoteLearner
+// HTTP endpoint below.
+func (s *Server) PromoteLearner(ctx context.Context, in *pb.PromoteLearnerRequest) (*pb.Status, error) {
+	if in.Id == 0 {
+		return nil, errors.New("id is required")
+	}
+
+	mm := s.member(in.Id)
+	if mm == nil {
+		return nil, errors.New("no known learner with that id")
+	}
+	if !mm.Learner {
+		return nil, errors.New("member is already a voter")
+	}
+
+	// Replicate the promotion through Raft first, the same way AddZeroPeer
+	// replicates the initial learner membership -- mutating mm.Learner
+	// directly here would race with concurrent readers of the member map and
+	// would never reach followers (or survive this leader's own restart).
+	start := time.Now()
+	err := s.Node.proposeAndWait(ctx, &pb.ZeroProposal{
+		Member: &pb.Member{Id: in.Id, Addr: mm.Addr, GroupId: 0, Learner: false},
+	})
+	recordProposalLatency(start)
+	if err != nil {
+		return nil, err
+	}
+
+	cc := raftpb.ConfChange{
+		Type:    raftpb.ConfChangeAddNode,
+		NodeID:  in.Id,
+		Context: []byte(mm.Addr),
+	}
+	if err := s.Node.Raft().ProposeConfChange(ctx, cc); err != nil {
+		// Same partial-failure hazard as AddZeroPeer: mm.Learner is already
+		// false in membership state, but Raft's conf state was never told
+		// about the promotion and never will be without a retry.
+		glog.Errorf("Zero peer %#x (%s) was recorded as a voter in membership state, but the "+
+			"Raft conf change to promote it failed and was not retried: %v", in.Id, mm.Addr, err)
+		return nil, fmt.Errorf("membership state updated but raft conf change failed for peer %#x, "+
+			"cluster state is now inconsistent: %w", in.Id, err)
+	}
+
+	recordGroupMembers(0, len(s.Node.Raft().Status().Progress))
+	glog.Infof("Promoted Zero learner %#x (%s) to voter", in.Id, mm.Addr)
+	return &pb.Status{Code: 0, Msg: "OK"}, nil
+}
+
+func (st *state) promoteLearner(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		x.SetStatus(w, x.ErrorInvalidMethod, "Invalid method")
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		x.SetStatus(w, x.Error, "valid id is required")
+		return
+	}
+
+	if _, err := st.zero.PromoteLearner(r.Context(), &pb.PromoteLearnerRequest{Id: id}); err != nil {
+		x.SetStatus(w, x.Error, err.Error())
+		return
+	}
+	x.WriteResponse(w, r, []byte("OK"))
+}
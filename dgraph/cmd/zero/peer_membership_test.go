@@ -0,0 +1,69 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// These cover only the input-validation paths that return before touching
+// st.zero/st.node -- exercising the Raft replication paths themselves would
+// need a real *node, which isn't constructible outside a running cluster.
+
+func TestAddZeroPeerRejectsWrongMethod(t *testing.T) {
+	st := &state{}
+	req := httptest.NewRequest(http.MethodGet, "/addZeroPeer", nil)
+	rr := httptest.NewRecorder()
+	st.addZeroPeer(rr, req)
+	require.NotEqual(t, http.StatusOK, rr.Code)
+}
+
+func TestAddZeroPeerRejectsMissingFields(t *testing.T) {
+	st := &state{}
+	req := httptest.NewRequest(http.MethodPost, "/addZeroPeer?id=0&addr=", nil)
+	rr := httptest.NewRecorder()
+	st.addZeroPeer(rr, req)
+	require.NotEqual(t, http.StatusOK, rr.Code)
+}
+
+func TestAddZeroPeerRejectsNonNumericID(t *testing.T) {
+	st := &state{}
+	req := httptest.NewRequest(http.MethodPost, "/addZeroPeer?id=not-a-number&addr=localhost:1", nil)
+	rr := httptest.NewRecorder()
+	st.addZeroPeer(rr, req)
+	require.NotEqual(t, http.StatusOK, rr.Code)
+}
+
+func TestPromoteLearnerRejectsWrongMethod(t *testing.T) {
+	st := &state{}
+	req := httptest.NewRequest(http.MethodGet, "/promoteLearner", nil)
+	rr := httptest.NewRecorder()
+	st.promoteLearner(rr, req)
+	require.NotEqual(t, http.StatusOK, rr.Code)
+}
+
+func TestPromoteLearnerRejectsMissingID(t *testing.T) {
+	st := &state{}
+	req := httptest.NewRequest(http.MethodPost, "/promoteLearner?id=0", nil)
+	rr := httptest.NewRecorder()
+	st.promoteLearner(rr, req)
+	require.NotEqual(t, http.StatusOK, rr.Code)
+}
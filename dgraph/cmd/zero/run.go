@@ -87,7 +87,10 @@ instances to achieve high-availability.
 	}
 	Zero.EnvPrefix = "DGRAPH_ZERO"
 
-	flag := Zero.Cmd.Flags()
+	// Use PersistentFlags (rather than Flags) so that every Zero setting is
+	// also visible to subcommands such as "zero export" -- Cobra does not
+	// pass a parent's local flags down to children during parsing.
+	flag := Zero.Cmd.PersistentFlags()
 	flag.String("my", "",
 		"addr:port of this server, so other Dgraph alphas can talk to this.")
 	flag.IntP("port_offset", "o", 0,
@@ -100,6 +103,8 @@ instances to achieve high-availability.
 	flag.Duration("rebalance_interval", 8*time.Minute, "Interval for trying a predicate move.")
 	flag.Bool("telemetry", true, "Send anonymous telemetry data to Dgraph devs.")
 	flag.Bool("enable_sentry", true, "Turn on/off sending events to Sentry. (default on)")
+	flag.Bool("metrics.prometheus", false,
+		"Expose Raft, tablet, rebalance and RPC metrics at /metrics in Prometheus exposition format.")
 
 	// OpenCensus flags.
 	flag.Float64("trace", 0.01, "The ratio of queries to trace.")
@@ -135,6 +140,31 @@ instances to achieve high-availability.
 			"log directory. mmap consumes more RAM, but provides better performance.")
 	flag.Int("badger.compression_level", 3,
 		"The compression level for Badger. A higher value uses more resources.")
+
+	// Audit flags
+	flag.String("audit.dir", "", "Directory to store structured JSON audit logs for admin operations.")
+	flag.Bool("audit.compress", false, "Compress rotated audit log files.")
+	flag.String("audit.encrypt_key_file", "",
+		"File containing the key used to encrypt audit log entries. Requires Enterprise Edition.")
+
+	flag.Int("grpc_web.max_message_size", 0,
+		"Max message size (in bytes) buffered by the grpc-web/websocket gateway. Defaults to x.GrpcMaxSize.")
+	flag.String("grpc_web.allowed_origins", "",
+		"Comma-separated list of origins allowed to call the grpc-web/websocket gateway. "+
+			"Empty (default) allows only same-origin requests.")
+
+	// Encryption flags
+	flag.String("encryption.kms", "",
+		"[file, aws, gcp, vault] Where to source the WAL Badger store's data encryption key from. "+
+			"Defaults to unencrypted if unset. aws, gcp and vault work only if a backend was "+
+			"registered under that name with enc.RegisterKMSBackend; otherwise Zero exits at startup.")
+	flag.String("encryption_key_file", "",
+		"File holding the WAL data encryption key (plaintext for 'file', KMS-wrapped otherwise).")
+	flag.Duration("encryption.key_rotation_interval", 0,
+		"Interval at which the WAL data encryption key is re-wrapped. 0 disables rotation.")
+
+	// config.go's own flags need Zero.Cmd, which is only set above.
+	registerConfigFlags()
 }
 
 func setupListener(addr string, port int, kind string) (listener net.Listener, err error) {
@@ -144,9 +174,10 @@ func setupListener(addr string, port int, kind string) (listener net.Listener, e
 }
 
 type state struct {
-	node *node
-	rs   *conn.RaftServer
-	zero *Server
+	node    *node
+	rs      *conn.RaftServer
+	zero    *Server
+	grpcSrv *grpc.Server
 }
 
 func (st *state) serveGRPC(l net.Listener, store *raftwal.DiskStorage) {
@@ -156,6 +187,7 @@ func (st *state) serveGRPC(l net.Listener, store *raftwal.DiskStorage) {
 		grpc.MaxSendMsgSize(x.GrpcMaxSize),
 		grpc.MaxConcurrentStreams(1000),
 		grpc.StatsHandler(&ocgrpc.ServerHandler{}),
+		grpc.UnaryInterceptor(st.auditUnaryInterceptor),
 	}
 
 	tlsConf, err := x.LoadServerTLSConfigForInternalPort(Zero.Conf.GetBool("tls_internal_port_enabled"), Zero.Conf.GetString("tls_dir"))
@@ -164,6 +196,7 @@ func (st *state) serveGRPC(l net.Listener, store *raftwal.DiskStorage) {
 		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(tlsConf)))
 	}
 	s := grpc.NewServer(grpcOpts...)
+	st.grpcSrv = s
 
 	rc := pb.RaftContext{Id: opts.nodeId, Addr: opts.myAddr, Group: 0}
 	m := conn.NewNode(&rc, store, opts.tlsClientConfig)
@@ -214,6 +247,8 @@ func run() {
 	}
 
 	x.PrintVersion()
+	x.Check(loadConfigFile())
+
 	var tlsDisRoutes []string
 	if Zero.Conf.GetString("tls_disabled_route") != "" {
 		tlsDisRoutes = strings.Split(Zero.Conf.GetString("tls_disabled_route"), ",")
@@ -301,6 +336,18 @@ func run() {
 		WithIndexCacheSize(indexCacheSz).
 		WithLoadBloomsOnOpen(false)
 
+	var kp enc.KeyProvider
+	if kmsKind := Zero.Conf.GetString("encryption.kms"); kmsKind != "" {
+		if !enc.EeBuild {
+			log.Fatalf("ERROR: encryption.kms option cannot be applied to OSS builds.")
+		}
+		kp, err = newKeyProvider(kmsKind, Zero.Conf.GetString("encryption_key_file"))
+		x.Checkf(err, "Error while setting up WAL encryption key provider")
+		dek, err := kp.DataKey()
+		x.Checkf(err, "Error while fetching WAL data encryption key")
+		kvOpt = kvOpt.WithEncryptionKey(dek)
+	}
+
 	compression_level := Zero.Conf.GetInt("badger.compression_level")
 	if compression_level > 0 {
 		// By default, compression is disabled in badger.
@@ -334,7 +381,7 @@ func run() {
 	defer kv.Close()
 
 	gcCloser := z.NewCloser(1) // closer for vLogGC
-	go x.RunVlogGC(kv, gcCloser)
+	go runVlogGCWithMetrics(kv, gcCloser)
 	defer gcCloser.SignalAndWait()
 
 	store := raftwal.Init(kv, opts.nodeId, 0)
@@ -346,14 +393,37 @@ func run() {
 	x.Check(err)
 	st.startListenHttpAndHttps(httpListener, tlsCfg)
 
+	metricsCloser := z.NewCloser(1)
+	go pollRaftAndStoreMetrics(&st, kv, metricsCloser)
+	defer metricsCloser.SignalAndWait()
+
+	if kp != nil {
+		rotationCloser := z.NewCloser(1)
+		startKeyRotation(kp, Zero.Conf.GetDuration("encryption.key_rotation_interval"),
+			st.zero.closer.Signal, rotationCloser)
+		defer rotationCloser.SignalAndWait()
+	}
+
+	x.Check(initAuditLogger())
+
 	http.HandleFunc("/health", st.pingResponse)
 	http.HandleFunc("/state", st.getState)
-	http.HandleFunc("/removeNode", st.removeNode)
-	http.HandleFunc("/moveTablet", st.moveTablet)
-	http.HandleFunc("/assign", st.assign)
-	http.HandleFunc("/enterpriseLicense", st.applyEnterpriseLicense)
+	http.HandleFunc("/removeNode", st.auditHTTPMiddleware("/removeNode", st.removeNode))
+	http.HandleFunc("/moveTablet",
+		st.auditHTTPMiddleware("/moveTablet", tabletMoveMetricsMiddleware(st.moveTablet)))
+	http.HandleFunc("/assign", st.auditHTTPMiddleware("/assign", st.assign))
+	http.HandleFunc("/enterpriseLicense", st.auditHTTPMiddleware("/enterpriseLicense", st.applyEnterpriseLicense))
+	http.HandleFunc("/addZeroPeer", st.auditHTTPMiddleware("/addZeroPeer", st.addZeroPeer))
+	http.HandleFunc("/promoteLearner", st.auditHTTPMiddleware("/promoteLearner", st.promoteLearner))
+	http.Handle("/api/grpc/", grpcWebHandler(st.grpcSrv, maxGrpcWebMessageSize(), allowedGrpcWebOrigins()))
 	zpages.Handle(http.DefaultServeMux, "/z")
 
+	if Zero.Conf.GetBool("metrics.prometheus") {
+		promHandler, err := registerPrometheusMetrics()
+		x.Checkf(err, "Error while registering Prometheus metrics")
+		http.Handle("/metrics", promHandler)
+	}
+
 	// This must be here. It does not work if placed before Grpc init.
 	x.Check(st.node.initAndStartNode())
 
@@ -0,0 +1,31 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zero
+
+import "github.com/spf13/viper"
+
+// withConf swaps Zero.Conf for a fresh viper.Viper populated with settings,
+// returning a func to restore the original. Tests that read flags/config via
+// Zero.Conf use this instead of depending on the real Cmd's flag parsing.
+func withConf(settings map[string]any) func() {
+	prev := Zero.Conf
+	Zero.Conf = viper.New()
+	for k, v := range settings {
+		Zero.Conf.Set(k, v)
+	}
+	return func() { Zero.Conf = prev }
+}
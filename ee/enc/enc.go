@@ -0,0 +1,32 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package enc holds encryption-at-rest helpers shared by Dgraph's Enterprise
+// Edition features (Alpha's export/backup encryption, Zero's WAL encryption).
+package enc
+
+import "io/ioutil"
+
+// EeBuild reports whether this binary was built with Enterprise Edition
+// features enabled. Commands that require a license (encryption, ACLs, audit
+// logging) check this before proceeding.
+var EeBuild = true
+
+// ReadKeyFromFile reads a key (plaintext for local use, or KMS-wrapped for
+// the cloud-backed KeyProvider implementations) from disk.
+func ReadKeyFromFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(path)
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2017-2018 Dgraph Labs, Inc. and Contributors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package enc
+
+import "fmt"
+
+// KeyProvider supplies the data encryption key (DEK) used to encrypt a
+// Badger store at rest, and knows how to rotate it. Callers (e.g. Zero's
+// WAL store) fetch the DEK once at startup via DataKey, and periodically
+// call Rotate to re-wrap it.
+type KeyProvider interface {
+	// DataKey returns the current plaintext DEK.
+	DataKey() ([]byte, error)
+	// Rotate re-wraps the DEK -- generating a new one for a local key file,
+	// or asking the backing KMS to issue/re-wrap one -- and returns the new
+	// plaintext DEK. Badger only needs the latest DEK to encrypt new writes;
+	// existing SSTables keep the data key they were written with recorded in
+	// Badger's own key registry, so rotating here never rewrites them.
+	Rotate() ([]byte, error)
+}
+
+// KMSUnwrapper decrypts ("unwraps") a DEK that a KMS previously wrapped, and
+// re-wraps a freshly rotated DEK. enc itself has no cloud SDK dependency and
+// registers no backend; an AWS KMS, GCP KMS or Vault implementation plugs in
+// through RegisterKMSBackend, so builds of Zero without one don't need
+// AWS/GCP/Vault credentials or SDKs just to compile.
+type KMSUnwrapper interface {
+	Unwrap(wrapped []byte) (dek []byte, err error)
+	Wrap(dek []byte) (wrapped []byte, err error)
+}
+
+var kmsBackends = map[string]func() (KMSUnwrapper, error){}
+
+// RegisterKMSBackend plugs in a KMSUnwrapper under a name usable with
+// --encryption.kms (e.g. "aws", "gcp", "vault"), typically from the init()
+// of the package that implements it.
+func RegisterKMSBackend(name string, factory func() (KMSUnwrapper, error)) {
+	kmsBackends[name] = factory
+}
+
+// KMSBackend looks up a KMSUnwrapper previously registered with
+// RegisterKMSBackend. It returns an error -- rather than a working but fake
+// unwrapper -- when nothing has registered under that name.
+func KMSBackend(name string) (KMSUnwrapper, error) {
+	factory, ok := kmsBackends[name]
+	if !ok {
+		return nil, fmt.Errorf("enc: no KMS backend registered for %q", name)
+	}
+	return factory()
+}